@@ -0,0 +1,93 @@
+package logtool
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// connWriter is a keep-alive TCP sink, e.g. for shipping log lines to a
+// remote log aggregator. Configure it via AddWriter("conn", jsonConfig)
+// with a JSON object like:
+//
+//	{"net":"tcp","addr":"127.0.0.1:7020","level":3,"reconnect":true,"reconnectOnMsg":false}
+type connWriter struct {
+	Net            string `json:"net"`
+	Addr           string `json:"addr"`
+	Level          Level  `json:"level"`
+	Reconnect      bool   `json:"reconnect"`
+	ReconnectOnMsg bool   `json:"reconnectOnMsg"`
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func init() {
+	RegisterWriter("conn", newConnWriter)
+}
+
+func newConnWriter(jsonConfig string) (Writer, error) {
+	w := &connWriter{Net: "tcp", Reconnect: true}
+	if jsonConfig != "" {
+		if err := json.Unmarshal([]byte(jsonConfig), w); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *connWriter) connect() error {
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+	conn, err := net.Dial(w.Net, w.Addr)
+	if err != nil {
+		return err
+	}
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		tcp.SetKeepAlive(true)
+	}
+	w.conn = conn
+	return nil
+}
+
+// write ignores fields: connWriter ships the already-rendered line, not a
+// structured one.
+func (w *connWriter) write(level Level, s string, fields Fields) {
+	if level < w.Level {
+		return
+	}
+	line := time.Now().Format("2006-01-02 15:04:05.999 ") + "[" + levelName[level] + "] " + s
+	if !strings.HasSuffix(line, "\n") {
+		line += "\n"
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.ReconnectOnMsg {
+		w.connect()
+	}
+	if w.conn == nil {
+		return
+	}
+	if _, err := w.conn.Write([]byte(line)); err != nil && w.Reconnect {
+		if w.connect() == nil {
+			w.conn.Write([]byte(line))
+		}
+	}
+}
+
+func (w *connWriter) exit() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+}
@@ -0,0 +1,56 @@
+package logtool
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ANSI colors per level, matching the usual debug/info/warn/error traffic-light
+// convention used by most terminal loggers.
+var levelColor = []string{
+	levelDefault: "\033[0m",
+	LevelDebug:   "\033[36m",
+	LevelInfo:    "\033[32m",
+	LevelWarn:    "\033[33m",
+	LevelError:   "\033[31m",
+	LevelAction:  "\033[35m",
+}
+
+const colorReset = "\033[0m"
+
+type consoleWriter struct {
+	Level Level `json:"level"`
+	Color bool  `json:"color"`
+}
+
+func init() {
+	RegisterWriter("console", newConsoleWriter)
+}
+
+func newConsoleWriter(jsonConfig string) (Writer, error) {
+	w := &consoleWriter{Color: true}
+	if jsonConfig != "" {
+		if err := json.Unmarshal([]byte(jsonConfig), w); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// write ignores fields: consoleWriter is a plain-text sink, not a
+// structured one.
+func (w *consoleWriter) write(level Level, s string, fields Fields) {
+	if level < w.Level {
+		return
+	}
+	line := time.Now().Format("2006-01-02 15:04:05.999") + " [" + levelName[level] + "] " + s
+	if w.Color {
+		fmt.Fprint(os.Stderr, levelColor[level]+line+colorReset+"\n")
+	} else {
+		fmt.Fprintln(os.Stderr, line)
+	}
+}
+
+func (w *consoleWriter) exit() {}
@@ -3,9 +3,13 @@ package logtool
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -15,6 +19,7 @@ const (
 	MaxSize       uint64 = 1024 * 1024 * 1800
 	bufferSize           = 256 * 1024
 	flushInterval        = 5 * time.Second
+	sweepInterval        = 24 * time.Hour
 )
 
 type fileLogWriter struct {
@@ -25,6 +30,27 @@ type fileLogWriter struct {
 	freeList   *buffer
 	freeListMu sync.Mutex
 	bufPool    sync.Pool
+	policy     rotatePolicy
+
+	async   bool
+	msgChan chan *logMsg
+	wg      sync.WaitGroup
+
+	stop     chan struct{}
+	exitOnce sync.Once
+}
+
+// logMsg is the unit of work handed from the caller's goroutine to the
+// async consumer: everything that must be captured synchronously (the
+// goroutine id and caller location, which depend on stack depth) travels
+// with it, while timestamp/line formatting is deferred to the consumer.
+type logMsg struct {
+	when   time.Time
+	level  Level
+	msg    string
+	fields Fields
+	goid   uint64
+	caller string
 }
 
 func newFileLog(logName, basePath string) *fileLogWriter {
@@ -32,18 +58,125 @@ func newFileLog(logName, basePath string) *fileLogWriter {
 		basePath: basePath,
 		logName:  logName,
 		bufPool:  sync.Pool{New: func() interface{} { return &bytes.Buffer{} }},
+		policy:   defaultRotatePolicy,
+		stop:     make(chan struct{}),
 	}
 	go writer.flushDaemon()
 	return writer
 }
 
+// Init configures the rotation policy from a JSON string, e.g.:
+//
+//	{"filename":"app.log","maxlines":100000,"maxsize":1073741824,
+//	 "daily":true,"hourly":false,"maxdays":7,"rotate":true,"perm":"0660"}
+//
+// Any key left out keeps its current-default value, so existing callers
+// that never call Init keep today's behavior (hourly rotation + the
+// package MaxSize, no retention sweep).
+func (w *fileLogWriter) Init(cfg string) error {
+	c, err := parseRotateConfig(cfg, defaultRotateConfig)
+	if err != nil {
+		return fmt.Errorf("logtool: invalid rotate config: %v", err)
+	}
+	policy, err := c.toPolicy()
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	if c.Filename != "" {
+		w.logName = c.Filename
+	}
+	w.policy = policy
+	w.mu.Unlock()
+
+	if c.MaxDays > 0 {
+		go w.sweepDaemon()
+	}
+	return nil
+}
+
+func (w *fileLogWriter) sweepDaemon() {
+	w.sweepOldLogs()
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.sweepOldLogs()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// sweepOldLogs walks logs/<name>/<yyyymm>/<dd>/ and unlinks files whose
+// mtime is older than MaxDays.
+func (w *fileLogWriter) sweepOldLogs() {
+	w.mu.Lock()
+	maxDays := w.policy.MaxDays
+	root := logRoot(w.basePath, w.logName)
+	w.mu.Unlock()
+	if maxDays <= 0 {
+		return
+	}
+	cutoff := time.Now().AddDate(0, 0, -maxDays)
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info == nil || info.IsDir() {
+			return nil
+		}
+		if info.ModTime().Before(cutoff) {
+			if err := os.Remove(path); err != nil {
+				fmt.Println("[logtool] maxdays sweep: cannot remove " + path + ": " + err.Error())
+			}
+		}
+		return nil
+	})
+}
+
+// SetAsync switches the writer to buffered async mode: write() enqueues
+// onto a channel of size bufLen and a single consumer goroutine drains it,
+// moving formatting and file I/O off the caller's goroutine.
+func (w *fileLogWriter) SetAsync(bufLen int) *fileLogWriter {
+	w.msgChan = make(chan *logMsg, bufLen)
+	w.wg.Add(1)
+	go w.asyncLoop()
+	w.async = true
+	return w
+}
+
+func (w *fileLogWriter) asyncLoop() {
+	defer w.wg.Done()
+	for m := range w.msgChan {
+		w.format(m)
+	}
+}
+
+// exit is idempotent: it's reachable both from a direct Exit() call and
+// from multiWriter.add() replacing this writer, and either path can run
+// more than once (a repeat Exit(), a second AddWriter for the same name).
 func (w *fileLogWriter) exit() {
-	w.flushAll()
+	w.exitOnce.Do(func() {
+		close(w.stop)
+		if w.async {
+			close(w.msgChan)
+			w.wg.Wait()
+		}
+		w.flushAll()
+		w.closeAll()
+	})
 }
 
 func (w *fileLogWriter) flushDaemon() {
-	for _ = range time.NewTicker(flushInterval).C {
-		w.flushAll()
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.flushAll()
+		case <-w.stop:
+			return
+		}
 	}
 }
 
@@ -58,6 +191,20 @@ func (w *fileLogWriter) flushAll() {
 	}
 }
 
+// closeAll closes the underlying *os.File of every per-level writer so
+// exit() actually releases file descriptors instead of just flushing them -
+// important now that AddWriter can replace a named writer at runtime and
+// the old one's exit() must leave nothing behind.
+func (w *fileLogWriter) closeAll() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, writer := range w.writers {
+		if writer != nil && writer.file != nil {
+			writer.file.Close()
+		}
+	}
+}
+
 type buffer struct {
 	bytes.Buffer
 	next *buffer
@@ -90,18 +237,92 @@ func (w *fileLogWriter) putBuffer(b *buffer) {
 	w.freeListMu.Unlock()
 }
 
-func (w *fileLogWriter) write(level Level, s string) {
+// callerSkip is the frame count from here up to the original caller of
+// Debug/Info/Warn/Error/Action (or a Logger's equivalent method): this
+// write, the multiWriter fan-out it's invoked through, the package-level
+// write(), and the one public function/method the user actually called.
+const callerSkip = 4
+
+// write implements the plain Writer interface: it always derives its own
+// goroutine id. It's only reached if something calls a fileLogWriter
+// through that interface directly instead of via multiWriter, which
+// dispatches to writeID instead (see idWriter in multiwriter.go).
+func (w *fileLogWriter) write(level Level, s string, fields Fields) {
+	w.writeID(level, s, fields, 0)
+}
+
+// writeID is write, but accepts a goroutine id the caller already has (see
+// Logger.cachedGoid in structured.go) instead of deriving one; id==0 means
+// "derive it fresh" (see the id param doc on the package-level write()).
+func (w *fileLogWriter) writeID(level Level, s string, fields Fields, id uint64) {
 	now := time.Now()
+	if id == 0 {
+		id = goid()
+	}
+	caller := "???"
+	if _, file, line, ok := runtime.Caller(callerSkip); ok {
+		_, filename := path.Split(file)
+		caller = filename + " " + strconv.Itoa(line)
+	}
+	m := &logMsg{when: now, level: level, msg: s, fields: fields, goid: id, caller: caller}
+	if w.async {
+		w.msgChan <- m
+		return
+	}
+	w.format(m)
+}
+
+func (w *fileLogWriter) format(m *logMsg) {
 	buf := bfPool.Get().(*bytes.Buffer)
-	if level != LevelAction {
-		timestamp := now.Format("2006-01-02 15:04:05.999 ")
-		buf.WriteString(timestamp)
+	if getFormat() == FormatJSON {
+		writeJSONRecord(buf, m)
+	} else {
+		writeTextRecord(buf, m)
+	}
+	w.doWrite(m.level, m.when, buf)
+}
+
+func writeTextRecord(buf *bytes.Buffer, m *logMsg) {
+	if m.level != LevelAction {
+		buf.WriteString(m.when.Format("2006-01-02 15:04:05.999 "))
+	}
+	buf.WriteString(reqidLabel(m.goid))
+	buf.WriteString(m.caller)
+	buf.WriteString(" : ")
+	buf.WriteString(m.msg)
+	if len(m.fields) > 0 {
+		buf.WriteString(" ")
+		if b, err := json.Marshal(m.fields); err == nil {
+			buf.Write(b)
+		}
 	}
-	buf.WriteString(GetPrefix(4))
-	buf.WriteString(s)
 	if buf.Bytes()[buf.Len()-1] != '\n' {
 		buf.WriteByte('\n')
 	}
+}
+
+func writeJSONRecord(buf *bytes.Buffer, m *logMsg) {
+	rec := jsonRecord{
+		TS:     m.when.Format("2006-01-02T15:04:05.999Z07:00"),
+		Level:  levelName[m.level],
+		Caller: m.caller,
+		GoID:   m.goid,
+		Msg:    m.msg,
+		Fields: m.fields,
+	}
+	if v, ok := m.fields["reqid"]; ok {
+		rec.ReqID = fmt.Sprint(v)
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		buf.WriteString(m.msg)
+	} else {
+		buf.Write(b)
+	}
+	buf.WriteByte('\n')
+}
+
+func (w *fileLogWriter) doWrite(level Level, now time.Time, buf *bytes.Buffer) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 	writer := w.writers[level]
@@ -110,6 +331,7 @@ func (w *fileLogWriter) write(level Level, s string) {
 			basePath: w.basePath,
 			logName:  w.logName,
 			level:    level,
+			owner:    w,
 		}
 		w.writers[level] = writer
 	}
@@ -129,6 +351,15 @@ type bufferWriter struct {
 	stime    time.Time
 	slot     int
 	nbytes   uint64 // The number of bytes written to this file
+	nlines   uint64 // The number of lines written to this file
+
+	// owner is read for its current policy on every checkRotate/rotateFile
+	// call rather than copying the policy in at construction time, so a
+	// SetRotatePolicy/Init() call takes effect immediately even for levels
+	// that have already logged (and so already have a bufferWriter). Every
+	// caller of checkRotate already holds owner.mu (see doWrite), so this
+	// read is synchronized with Init()'s write under the same lock.
+	owner *fileLogWriter
 }
 
 func (sb *bufferWriter) Sync() error {
@@ -138,24 +369,30 @@ func (sb *bufferWriter) Sync() error {
 func (sb *bufferWriter) Write(p []byte) (int, error) {
 	n, err := sb.Writer.Write(p)
 	sb.nbytes += uint64(n)
+	sb.nlines += uint64(bytes.Count(p[:n], []byte{'\n'}))
 	return n, err
 }
 
 func (sb *bufferWriter) checkRotate(now time.Time) error {
+	policy := sb.owner.policy
 	if sb.file == nil {
 		return sb.rotateFile(now, 0)
 	}
 	syear, smonth, sday := sb.stime.Date()
 	year, month, day := now.Date()
-	if year != syear || month != smonth || day != sday {
+	if policy.Daily && (year != syear || month != smonth || day != sday) {
 		return sb.rotateFile(now, 0)
 	}
-	hour := now.Hour()
-	shour := sb.stime.Hour()
-	if hour != shour {
+	if policy.Hourly && now.Hour() != sb.stime.Hour() {
 		return sb.rotateFile(now, 0)
 	}
-	if sb.nbytes >= MaxSize {
+	if !policy.Rotate {
+		return nil
+	}
+	if policy.MaxSize > 0 && sb.nbytes >= uint64(policy.MaxSize) {
+		return sb.rotateFile(now, sb.slot+1)
+	}
+	if policy.MaxLines > 0 && sb.nlines >= uint64(policy.MaxLines) {
 		return sb.rotateFile(now, sb.slot+1)
 	}
 	return nil
@@ -166,29 +403,34 @@ func (sb *bufferWriter) rotateFile(now time.Time, slot int) error {
 		sb.Flush()
 		sb.file.Close()
 	}
-	var err error
-	file, err := createFile(sb.basePath, sb.logName, sb.level, slot, now)
+	file, err := createFile(sb.basePath, sb.logName, sb.level, slot, now, sb.owner.policy.Perm)
 	if err != nil {
 		return err
 	}
 	sb.file = file
 	sb.nbytes = 0
+	sb.nlines = 0
 	sb.stime = now
 	sb.slot = slot
-	if err != nil {
-		return err
-	}
 	sb.Writer = bufio.NewWriterSize(sb.file, bufferSize)
-	return err
+	return nil
 }
 
-func createFile(basePath, logName string, level Level, slot int, t time.Time) (*os.File, error) {
-	year, month, day := t.Date()
+// logRoot returns the "<basePath>/logs/<logName>" directory that
+// createFile and the maxdays sweeper both operate under.
+func logRoot(basePath, logName string) string {
 	if !strings.HasSuffix(basePath, "/") {
 		basePath += "/"
 	}
-	basePath += "logs"
-	logDir := filepath.Join(basePath, fmt.Sprintf("%s/%04d%02d/%02d/", logName, year, month, day))
+	return filepath.Join(basePath+"logs", logName)
+}
+
+func createFile(basePath, logName string, level Level, slot int, t time.Time, perm os.FileMode) (*os.File, error) {
+	if perm == 0 {
+		perm = 0666
+	}
+	year, month, day := t.Date()
+	logDir := filepath.Join(logRoot(basePath, logName), fmt.Sprintf("%04d%02d/%02d/", year, month, day))
 	err := os.MkdirAll(logDir, os.ModePerm)
 	if err != nil {
 		return nil, fmt.Errorf("logtool: cannot create log: %v", err)
@@ -200,7 +442,7 @@ func createFile(basePath, logName string, level Level, slot int, t time.Time) (*
 		logFile = fmt.Sprintf("%s-%02d-%d.log", levelName[level], t.Hour(), slot)
 	}
 	fname := filepath.Join(logDir, logFile)
-	f, err := os.OpenFile(fname, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	f, err := os.OpenFile(fname, os.O_RDWR|os.O_CREATE|os.O_APPEND, perm)
 	if err != nil {
 		return nil, fmt.Errorf("logtool: cannot open log file: %v", err)
 	}
@@ -1,9 +1,11 @@
 package logtool
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"testing"
+	"time"
 )
 
 //检查/data/logs/logtool_test/下是否有相应的日志
@@ -42,3 +44,58 @@ func Test_Pre(t *testing.T) {
 	Info("test wait")
 
 }
+
+func Test_Structured(t *testing.T) {
+	Init("logtool_test", LevelDebug, true)
+	SetFormat(FormatJSON)
+	logger := WithFields(Fields{"component": "filelog_test"})
+	logger.Info("test structured info", Fields{"reqid": "abc-123"})
+	logger.Warn("test structured warn")
+	ctx := ContextWithFields(context.Background(), Fields{"component": "ctx"})
+	WithContext(ctx).Error("test structured error")
+	SetFormat(FormatText)
+}
+
+func Test_Sampler(t *testing.T) {
+	Init("logtool_test", LevelDebug, true)
+	SetSampler(LevelWarn, NewRateSampler(5, 2))
+	SetSampler(LevelError, NewEveryNSampler(3))
+	SetSampler(LevelInfo, NewFirstNThenEveryK(2, 10, time.Minute))
+	for i := 0; i < 10; i++ {
+		Warn("flood warning")
+		Error("flood error")
+		Info("flood info")
+	}
+	SetSampler(LevelWarn, nil)
+	SetSampler(LevelError, nil)
+	SetSampler(LevelInfo, nil)
+}
+
+func Test_LoggerCachesGoid(t *testing.T) {
+	Init("logtool_test", LevelDebug, true)
+	logger := WithFields(Fields{"component": "filelog_test"})
+	id := logger.cachedGoid()
+	if got := logger.cachedGoid(); got != id {
+		t.Fatalf("cachedGoid changed across calls: %d then %d", id, got)
+	}
+	logger.Info("reuses cached goid")
+	logger.Info("reuses cached goid again")
+}
+
+func Benchmark_FileLogSync(b *testing.B) {
+	Init("logtool_bench_sync", LevelDebug, false)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Info("benchmark sync message")
+	}
+}
+
+func Benchmark_FileLogAsync(b *testing.B) {
+	Init("logtool_bench_async", LevelDebug, false)
+	SetAsync(1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Info("benchmark async message")
+	}
+	Exit()
+}
@@ -0,0 +1,41 @@
+package logtool
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// goid returns the current goroutine's numeric id, parsed off the runtime
+// stack trace header ("goroutine 123 [running]:"). This parse itself runs
+// every time goid() is called - there is no safe (non-unsafe/linkname) way
+// to identify the calling goroutine without it. What reqidLabel below
+// caches is the formatted label built *from* an id, saving the repeat
+// string-building/allocation once an id is known. Actually amortizing the
+// goid() call itself requires a place to stash the result across calls on
+// the same goroutine - see Logger.cachedGoid in structured.go, which does
+// that for request-scoped loggers (the plain package-level Debug/Info/
+// Warn/Error have no such per-call state, so they still call goid() fresh
+// every time).
+func goid() uint64 {
+	b := make([]byte, 64)
+	b = b[:runtime.Stack(b, false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	b = b[:bytes.IndexByte(b, ' ')]
+	n, _ := strconv.ParseUint(string(b), 10, 64)
+	return n
+}
+
+var reqidLabels sync.Map // uint64 goid -> string "reqid-N "
+
+// reqidLabel returns the "reqid-N " text label for id, building and
+// caching it the first time id is seen.
+func reqidLabel(id uint64) string {
+	if v, ok := reqidLabels.Load(id); ok {
+		return v.(string)
+	}
+	s := "reqid-" + strconv.FormatUint(id, 10) + " "
+	reqidLabels.Store(id, s)
+	return s
+}
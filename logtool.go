@@ -18,7 +18,7 @@ import (
 var inited bool
 
 type Writer interface {
-	write(Level, string)
+	write(Level, string, Fields)
 	exit()
 }
 
@@ -78,7 +78,7 @@ var (
 	logName    = "logtool"
 	logLevel   = LevelDebug
 	alsoStdout = false
-	logWriter  Writer
+	logWriter  *multiWriter
 	mu         sync.Mutex
 	skip       = 3
 )
@@ -102,7 +102,8 @@ func initWriter() {
 	if flagLogpath != "" {
 		logPath = flagLogpath
 	}
-	logWriter = newFileLog(logName, logPath)
+	logWriter = newMultiWriter()
+	logWriter.add("file", newFileLog(logName, logPath))
 }
 
 func Init(logName string, logLevel Level, stdOut bool) {
@@ -140,15 +141,25 @@ func SetLogPath(path string) {
 	}
 }
 
-func write(level Level, msg string) {
+// write logs msg at level. id, if nonzero, is a goroutine id the caller has
+// already derived via goid() (see Logger.cachedGoid in structured.go) and
+// is passed through instead of having fileLogWriter derive it again; 0
+// means "derive it fresh" (goroutine ids are never 0, so it's a safe
+// sentinel). Plain Debug/Info/Warn/Error calls always pass 0: there's no
+// per-call state to cache an id into, so they still pay for a fresh
+// runtime.Stack parse every time, same as before.
+func write(level Level, msg string, fields Fields, id uint64) {
 	if !inited {
 		fmt.Println(time.Now().Format("2006-01-02 15:04:05 ") + GetPrefix(skip) + " [" + levelName[level] + "] " + msg)
 		return
 	}
+	if !sample(level, msg) {
+		return
+	}
 	if logWriter == nil {
 		initWriter()
 	}
-	logWriter.write(level, msg)
+	logWriter.write(level, msg, fields, id)
 	if alsoStdout {
 		fmt.Println(time.Now().Format("2006-01-02 15:04:05") + " [" + levelName[level] + "] " + msg)
 	}
@@ -158,6 +169,31 @@ func SetSkip(s int) {
 	skip = s
 }
 
+// SetAsync switches the file writer to buffered async mode: see
+// fileLogWriter.SetAsync for details. If no writer has been created yet, it
+// creates the default file writer first.
+func SetAsync(bufLen int) {
+	if logWriter == nil {
+		initWriter()
+	}
+	if fw, ok := logWriter.get("file").(*fileLogWriter); ok {
+		fw.SetAsync(bufLen)
+	}
+}
+
+// SetRotatePolicy configures the file writer's rotation policy from a JSON
+// string; see fileLogWriter.Init for the accepted shape.
+func SetRotatePolicy(cfg string) error {
+	if logWriter == nil {
+		initWriter()
+	}
+	fw, ok := logWriter.get("file").(*fileLogWriter)
+	if !ok {
+		return errors.New("logtool: no file writer configured")
+	}
+	return fw.Init(cfg)
+}
+
 var bfPool sync.Pool
 
 func init() {
@@ -167,13 +203,8 @@ func init() {
 func GetPrefix(skip int) string {
 	_, file, line, ok := runtime.Caller(skip)
 
-	b := make([]byte, 64)
-	b = b[:runtime.Stack(b, false)]
-	b = bytes.TrimPrefix(b, []byte("goroutine "))
-	b = b[:bytes.IndexByte(b, ' ')]
-	n, _ := strconv.ParseUint(string(b), 10, 64)
 	buf := bfPool.Get().(*bytes.Buffer)
-	buf.WriteString(fmt.Sprintf("reqid-%d ", n))
+	buf.WriteString(reqidLabel(goid()))
 	if ok {
 		_, filename := path.Split(file)
 		buf.WriteString(filename)
@@ -202,73 +233,73 @@ func IsDebug() bool {
 
 func Debug(str string) {
 	if level() <= LevelDebug {
-		write(LevelDebug, str)
+		write(LevelDebug, str, nil, 0)
 	}
 }
 
 func Debugs(args ...interface{}) {
 	if level() <= LevelDebug {
-		write(LevelDebug, fmt.Sprintln(args...))
+		write(LevelDebug, fmt.Sprintln(args...), nil, 0)
 	}
 }
 
 func Debugf(format string, args ...interface{}) {
 	if level() <= LevelDebug {
-		write(LevelDebug, fmt.Sprintf(format, args...))
+		write(LevelDebug, fmt.Sprintf(format, args...), nil, 0)
 	}
 }
 
 func Info(str string) {
 	if level() <= LevelInfo {
-		write(LevelInfo, str)
+		write(LevelInfo, str, nil, 0)
 	}
 }
 
 func Infos(args ...interface{}) {
 	if level() <= LevelInfo {
-		write(LevelInfo, fmt.Sprintln(args...))
+		write(LevelInfo, fmt.Sprintln(args...), nil, 0)
 	}
 }
 
 func Infof(format string, args ...interface{}) {
 	if level() <= LevelInfo {
-		write(LevelInfo, fmt.Sprintf(format, args...))
+		write(LevelInfo, fmt.Sprintf(format, args...), nil, 0)
 	}
 }
 
 func Warn(str string) {
 	if level() <= LevelWarn {
-		write(LevelWarn, str)
+		write(LevelWarn, str, nil, 0)
 	}
 }
 
 func Warns(args ...interface{}) {
 	if level() <= LevelWarn {
-		write(LevelWarn, fmt.Sprintln(args...))
+		write(LevelWarn, fmt.Sprintln(args...), nil, 0)
 	}
 }
 
 func Warnf(format string, args ...interface{}) {
 	if level() <= LevelWarn {
-		write(LevelWarn, fmt.Sprintf(format, args...))
+		write(LevelWarn, fmt.Sprintf(format, args...), nil, 0)
 	}
 }
 
 func Error(str string) {
 	if level() <= LevelError {
-		write(LevelError, str)
+		write(LevelError, str, nil, 0)
 	}
 }
 
 func Errors(args ...interface{}) {
 	if level() <= LevelError {
-		write(LevelError, fmt.Sprintln(args...))
+		write(LevelError, fmt.Sprintln(args...), nil, 0)
 	}
 }
 
 func Errorf(format string, args ...interface{}) {
 	if level() <= LevelError {
-		write(LevelError, fmt.Sprintf(format, args...))
+		write(LevelError, fmt.Sprintf(format, args...), nil, 0)
 	}
 }
 
@@ -277,7 +308,7 @@ func Action(v interface{}) error {
 	if err != nil {
 		return errors.New("action data is empty")
 	}
-	write(LevelAction, string(str))
+	write(LevelAction, string(str), nil, 0)
 	return nil
 }
 
@@ -315,7 +346,7 @@ func (this *LogWriter) Write(data []byte) (int, error) {
 	}
 
 	if l == levelDefault || level() <= l {
-		write(l, string(data))
+		write(l, string(data), nil, 0)
 	}
 	return len(data), nil
 }
@@ -0,0 +1,102 @@
+package logtool
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+)
+
+// multiFileWriter keeps one fileLogWriter under <name> that receives every
+// level, plus one under <name>.<level> for each level listed in "separate".
+// Like any fileLogWriter, the one under <name> still writes each level to
+// its own file (error-HH.log, info-HH.log, ...); no single file holds all
+// levels together. Configure it via
+// AddWriter("multifile", jsonConfig) with a JSON object like:
+//
+//	{"filename":"app.log","separate":["error","warn"],"maxdays":7}
+//
+// Every sub-writer shares the same rotation policy.
+type multiFileWriter struct {
+	basePath string
+	all      *fileLogWriter
+	separate map[Level]*fileLogWriter
+}
+
+func init() {
+	RegisterWriter("multifile", newMultiFileWriter)
+}
+
+type multiFileConfig struct {
+	rotateConfig
+	Separate []string `json:"separate"`
+}
+
+func newMultiFileWriter(jsonConfig string) (Writer, error) {
+	cfg := multiFileConfig{rotateConfig: defaultRotateConfig}
+	if jsonConfig != "" {
+		if err := json.Unmarshal([]byte(jsonConfig), &cfg); err != nil {
+			return nil, err
+		}
+	}
+	policy, err := cfg.rotateConfig.toPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	base := cfg.Filename
+	if base == "" {
+		base = logName
+	}
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+
+	w := &multiFileWriter{
+		basePath: logPath,
+		separate: make(map[Level]*fileLogWriter, len(cfg.Separate)),
+	}
+	w.all = newFileLog(base, w.basePath)
+	w.all.policy = policy
+
+	for _, name := range cfg.Separate {
+		level := levelForName(name)
+		if level == levelDefault {
+			continue
+		}
+		sub := newFileLog(base+"."+name, w.basePath)
+		sub.policy = policy
+		w.separate[level] = sub
+	}
+	if policy.MaxDays > 0 {
+		go w.all.sweepDaemon()
+		for _, sub := range w.separate {
+			go sub.sweepDaemon()
+		}
+	}
+	return w, nil
+}
+
+func levelForName(name string) Level {
+	for k, v := range levelName {
+		if v == name {
+			return Level(k)
+		}
+	}
+	return levelDefault
+}
+
+func (w *multiFileWriter) write(level Level, s string, fields Fields) {
+	w.writeID(level, s, fields, 0)
+}
+
+func (w *multiFileWriter) writeID(level Level, s string, fields Fields, id uint64) {
+	w.all.writeID(level, s, fields, id)
+	if sub, ok := w.separate[level]; ok {
+		sub.writeID(level, s, fields, id)
+	}
+}
+
+func (w *multiFileWriter) exit() {
+	w.all.exit()
+	for _, sub := range w.separate {
+		sub.exit()
+	}
+}
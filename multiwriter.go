@@ -0,0 +1,98 @@
+package logtool
+
+import (
+	"errors"
+	"sync"
+)
+
+// WriterFactory builds a Writer from a JSON configuration string, following
+// the same "register a constructor, configure with JSON" pattern as the
+// stdlib sql drivers and beego's logger backends.
+type WriterFactory func(jsonConfig string) (Writer, error)
+
+var writerFactories = map[string]WriterFactory{}
+
+// RegisterWriter makes a named Writer backend available to AddWriter.
+// Built-in backends ("console", "conn", "smtp", "multifile") register
+// themselves in init(); further backends (e.g. a kafka one) register the
+// same way before calling AddWriter. Writer's methods are unexported, so
+// only code inside this package can implement one.
+func RegisterWriter(name string, factory WriterFactory) {
+	writerFactories[name] = factory
+}
+
+// multiWriter fans a single write() out to every registered sink so that,
+// for example, Error(...) can hit the file, stderr and a remote aggregator
+// at the same time.
+type multiWriter struct {
+	mu      sync.RWMutex
+	writers map[string]Writer
+}
+
+func newMultiWriter() *multiWriter {
+	return &multiWriter{writers: make(map[string]Writer)}
+}
+
+func (m *multiWriter) add(name string, w Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if old, ok := m.writers[name]; ok {
+		old.exit()
+	}
+	m.writers[name] = w
+}
+
+func (m *multiWriter) get(name string) Writer {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.writers[name]
+}
+
+// idWriter is implemented by writers that can accept a precomputed
+// goroutine id instead of deriving their own (currently just
+// fileLogWriter/multiFileWriter), letting a cached id (see Logger in
+// structured.go) skip a redundant runtime.Stack parse.
+type idWriter interface {
+	writeID(level Level, s string, fields Fields, id uint64)
+}
+
+func (m *multiWriter) write(level Level, s string, fields Fields, id uint64) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, w := range m.writers {
+		if iw, ok := w.(idWriter); ok {
+			iw.writeID(level, s, fields, id)
+		} else {
+			w.write(level, s, fields)
+		}
+	}
+}
+
+func (m *multiWriter) exit() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, w := range m.writers {
+		w.exit()
+	}
+}
+
+// AddWriter configures and registers an additional sink under name (e.g.
+// logtool.AddWriter("conn", jsonCfg)). It may be called before or after
+// Init. The sink is built from jsonConfig right away (a "conn" sink dials
+// its address here), and if no writer exists yet the default file writer is
+// created first, under the log name and path set at that moment.
+func AddWriter(name string, jsonConfig string) error {
+	factory, ok := writerFactories[name]
+	if !ok {
+		return errors.New("logtool: unknown writer \"" + name + "\"")
+	}
+	w, err := factory(jsonConfig)
+	if err != nil {
+		return err
+	}
+	if logWriter == nil {
+		initWriter()
+	}
+	logWriter.add(name, w)
+	return nil
+}
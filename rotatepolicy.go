@@ -0,0 +1,86 @@
+package logtool
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// rotatePolicy is the resolved (defaults-applied) rotation policy a
+// bufferWriter rotates against.
+type rotatePolicy struct {
+	MaxLines int         // rotate after this many lines; 0 disables
+	MaxSize  int64       // rotate after this many bytes; 0 disables
+	Daily    bool        // rotate when the calendar day changes
+	Hourly   bool        // rotate when the hour changes
+	MaxDays  int         // delete files older than this many days; 0 disables
+	Rotate   bool        // master switch for size/line-based rotation
+	Perm     os.FileMode // file mode used when creating new log files
+}
+
+// defaultRotatePolicy reproduces the writer's historical hard-coded
+// behavior: hourly rotation plus the package MaxSize, no line or
+// retention limits.
+var defaultRotatePolicy = rotatePolicy{
+	MaxSize: int64(MaxSize),
+	Daily:   true,
+	Hourly:  true,
+	Rotate:  true,
+	Perm:    0666,
+}
+
+// defaultRotateConfig is defaultRotatePolicy in the JSON-config shape, used
+// to fill in keys a caller's config string leaves out.
+var defaultRotateConfig = rotateConfig{
+	MaxSize: int64(MaxSize),
+	Daily:   true,
+	Hourly:  true,
+	Rotate:  true,
+	Perm:    "0666",
+}
+
+// rotateConfig is the JSON shape accepted by fileLogWriter.Init and the
+// multifile writer, e.g.:
+//
+//	{"filename":"app.log","maxlines":100000,"maxsize":1073741824,
+//	 "daily":true,"hourly":false,"maxdays":7,"rotate":true,"perm":"0660"}
+type rotateConfig struct {
+	Filename string `json:"filename"`
+	MaxLines int    `json:"maxlines"`
+	MaxSize  int64  `json:"maxsize"`
+	Daily    bool   `json:"daily"`
+	Hourly   bool   `json:"hourly"`
+	MaxDays  int    `json:"maxdays"`
+	Rotate   bool   `json:"rotate"`
+	Perm     string `json:"perm"`
+	Level    Level  `json:"level"`
+}
+
+// parseRotateConfig unmarshals cfg over defaults, so any key cfg omits
+// keeps its default value.
+func parseRotateConfig(cfg string, defaults rotateConfig) (rotateConfig, error) {
+	c := defaults
+	if cfg != "" {
+		if err := json.Unmarshal([]byte(cfg), &c); err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
+
+func (c rotateConfig) toPolicy() (rotatePolicy, error) {
+	perm, err := strconv.ParseUint(c.Perm, 8, 32)
+	if err != nil {
+		return rotatePolicy{}, fmt.Errorf("logtool: invalid perm %q: %v", c.Perm, err)
+	}
+	return rotatePolicy{
+		MaxLines: c.MaxLines,
+		MaxSize:  c.MaxSize,
+		Daily:    c.Daily,
+		Hourly:   c.Hourly,
+		MaxDays:  c.MaxDays,
+		Rotate:   c.Rotate,
+		Perm:     os.FileMode(perm),
+	}, nil
+}
@@ -0,0 +1,189 @@
+package logtool
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sampler decides whether a given message should be logged. Allow runs on
+// the hot path (every write() call for its level), so implementations must
+// be cheap and safe for concurrent use; all built-in samplers are lock-free.
+type Sampler interface {
+	Allow(msg string) bool
+}
+
+// samplerBox gives every SetSampler call the same concrete type to Store,
+// since atomic.Value panics if stored values aren't all of the same
+// concrete type and callers may swap in a different Sampler implementation
+// for the same level over the program's lifetime.
+type samplerBox struct{ s Sampler }
+
+var samplers [6]atomic.Value // holds samplerBox per Level
+
+// SetSampler installs s in front of write() for level: write() calls
+// s.Allow(msg) and drops the message if it returns false. Pass a nil
+// Sampler to remove one.
+func SetSampler(lvl Level, s Sampler) {
+	samplers[lvl].Store(samplerBox{s})
+}
+
+// sample reports whether msg should be logged at level, consulting any
+// Sampler installed via SetSampler.
+func sample(lvl Level, msg string) bool {
+	box, _ := samplers[lvl].Load().(samplerBox)
+	if box.s == nil {
+		return true
+	}
+	return box.s.Allow(msg)
+}
+
+// summaryInterval bounds how often a sampler will print its
+// "logtool: dropped N messages" summary.
+const summaryInterval = 10 * time.Second
+
+// dropCounter is the lock-free "how many did we drop, and when did we last
+// say so" accounting shared by every built-in Sampler.
+type dropCounter struct {
+	dropped     int64
+	lastSummary int64
+}
+
+func (d *dropCounter) recordDrop() {
+	atomic.AddInt64(&d.dropped, 1)
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&d.lastSummary)
+	if now-last < int64(summaryInterval) {
+		return
+	}
+	if !atomic.CompareAndSwapInt64(&d.lastSummary, last, now) {
+		return
+	}
+	if n := atomic.SwapInt64(&d.dropped, 0); n > 0 {
+		fmt.Println(fmt.Sprintf("logtool: dropped %d messages", n))
+	}
+}
+
+// rateSampler is a lock-free token-bucket limiter (GCRA): Allow grants up
+// to rate events/sec with bursts of burst, dropping the rest.
+type rateSampler struct {
+	dropCounter
+	intervalNanos int64
+	burstNanos    int64
+	nextSlot      int64 // atomic: nanosecond timestamp of the next free slot
+}
+
+// NewRateSampler allows up to rate events/sec, with bursts of up to burst
+// events absorbed immediately.
+func NewRateSampler(rate float64, burst int) *rateSampler {
+	interval := int64(float64(time.Second) / rate)
+	if interval <= 0 {
+		interval = 1
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	burstNanos := interval * int64(burst)
+	return &rateSampler{
+		intervalNanos: interval,
+		burstNanos:    burstNanos,
+		// Start with the full burst already banked, so messages submitted
+		// immediately after construction are absorbed instead of waiting
+		// out a full interval first.
+		nextSlot: time.Now().UnixNano() - burstNanos,
+	}
+}
+
+func (s *rateSampler) Allow(msg string) bool {
+	now := time.Now().UnixNano()
+	for {
+		prev := atomic.LoadInt64(&s.nextSlot)
+		next := prev + s.intervalNanos
+		if floor := now - s.burstNanos + s.intervalNanos; next < floor {
+			// Idle for a while: don't let unused capacity bank forever.
+			next = floor
+		}
+		if next > now {
+			s.recordDrop()
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&s.nextSlot, prev, next) {
+			return true
+		}
+	}
+}
+
+// everyNSampler logs 1 message out of every n, regardless of content.
+type everyNSampler struct {
+	dropCounter
+	n       int64
+	counter int64
+}
+
+// NewEveryNSampler logs every nth call and drops the rest.
+func NewEveryNSampler(n int) *everyNSampler {
+	if n < 1 {
+		n = 1
+	}
+	return &everyNSampler{n: int64(n)}
+}
+
+func (s *everyNSampler) Allow(msg string) bool {
+	if (atomic.AddInt64(&s.counter, 1)-1)%s.n == 0 {
+		return true
+	}
+	s.recordDrop()
+	return false
+}
+
+// firstNThenEveryK tail-samples by message content: within each interval
+// window, the first firstN occurrences of a given message (hashed with
+// fnv64) log normally, then 1-in-everyK after that.
+type firstNThenEveryK struct {
+	dropCounter
+	firstN   int64
+	everyK   int64
+	interval time.Duration
+	windows  sync.Map // uint64 msg hash -> *sampleWindow
+}
+
+type sampleWindow struct {
+	start int64 // atomic: unix nanos the current window started
+	count int64 // atomic: occurrences seen in the current window
+}
+
+// NewFirstNThenEveryK logs the first firstN occurrences of each distinct
+// message per interval, then 1-in-everyK thereafter.
+func NewFirstNThenEveryK(firstN, everyK int, interval time.Duration) *firstNThenEveryK {
+	if everyK < 1 {
+		everyK = 1
+	}
+	return &firstNThenEveryK{firstN: int64(firstN), everyK: int64(everyK), interval: interval}
+}
+
+func (s *firstNThenEveryK) Allow(msg string) bool {
+	h := fnv.New64a()
+	h.Write([]byte(msg))
+	key := h.Sum64()
+
+	bucket := time.Now().Truncate(s.interval).UnixNano()
+	v, _ := s.windows.LoadOrStore(key, &sampleWindow{start: bucket})
+	w := v.(*sampleWindow)
+	if start := atomic.LoadInt64(&w.start); start != bucket {
+		if atomic.CompareAndSwapInt64(&w.start, start, bucket) {
+			atomic.StoreInt64(&w.count, 0)
+		}
+	}
+
+	n := atomic.AddInt64(&w.count, 1)
+	if n <= s.firstN {
+		return true
+	}
+	if (n-s.firstN)%s.everyK == 0 {
+		return true
+	}
+	s.recordDrop()
+	return false
+}
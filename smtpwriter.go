@@ -0,0 +1,93 @@
+package logtool
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/smtp"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// smtpWriter emails an alert for messages at or above Level, e.g. to
+// page on-call on errors. Configure it via AddWriter("smtp", jsonConfig)
+// with a JSON object like:
+//
+//	{"username":"alerts","password":"...","host":"smtp.example.com:25",
+//	 "fromAddress":"alerts@example.com","sendTos":["oncall@example.com"],
+//	 "subject":"logtool alert","level":4,"minIntervalSec":60}
+//
+// minIntervalSec debounces sends (default 60s): an error storm dials out
+// to the SMTP server at most once per interval instead of once per message,
+// and messages arriving within the interval are not sent.
+type smtpWriter struct {
+	Username       string   `json:"username"`
+	Password       string   `json:"password"`
+	Host           string   `json:"host"`
+	Subject        string   `json:"subject"`
+	FromAddress    string   `json:"fromAddress"`
+	SendTos        []string `json:"sendTos"`
+	Level          Level    `json:"level"`
+	MinIntervalSec int      `json:"minIntervalSec"`
+
+	lastSent int64 // atomic unix nanos of the last mail dispatched
+}
+
+func init() {
+	RegisterWriter("smtp", newSmtpWriter)
+}
+
+func newSmtpWriter(jsonConfig string) (Writer, error) {
+	w := &smtpWriter{Level: LevelError, Subject: "logtool alert", MinIntervalSec: 60}
+	if jsonConfig != "" {
+		if err := json.Unmarshal([]byte(jsonConfig), w); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// write ignores fields: an alert email is the plain message text, not a
+// structured payload.
+func (w *smtpWriter) write(level Level, s string, fields Fields) {
+	if level < w.Level || len(w.SendTos) == 0 {
+		return
+	}
+	if !w.allowSend() {
+		return
+	}
+	go w.sendMail("[" + levelName[level] + "] " + s)
+}
+
+// allowSend reports whether enough time has passed since the last mail to
+// send another one, debouncing an error storm down to one dial-out per
+// MinIntervalSec instead of one per message.
+func (w *smtpWriter) allowSend() bool {
+	interval := time.Duration(w.MinIntervalSec) * time.Second
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&w.lastSent)
+	if now-last < int64(interval) {
+		return false
+	}
+	return atomic.CompareAndSwapInt64(&w.lastSent, last, now)
+}
+
+func (w *smtpWriter) sendMail(body string) {
+	host := w.Host
+	if idx := strings.IndexByte(host, ':'); idx >= 0 {
+		host = host[:idx]
+	}
+	auth := smtp.PlainAuth("", w.Username, w.Password, host)
+	msg := []byte("To: " + strings.Join(w.SendTos, ";") +
+		"\r\nFrom: " + w.FromAddress +
+		"\r\nSubject: " + w.Subject +
+		"\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n" + body)
+	if err := smtp.SendMail(w.Host, auth, w.FromAddress, w.SendTos, msg); err != nil {
+		fmt.Println("[logtool] smtpWriter: send mail failed: " + err.Error())
+	}
+}
+
+func (w *smtpWriter) exit() {}